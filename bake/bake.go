@@ -0,0 +1,159 @@
+// Package bake implements the code generation behind the gobake command,
+// for use by other tools composing it into a larger go:generate pipeline
+// without shelling out.
+package bake
+
+import (
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Options configures Generate.
+type Options struct {
+	// Decl selects how the value is declared: "func" (default), "const",
+	// "var", or "fs". Ignored if Paths is non-empty.
+	Decl string
+	// Compress selects how the value is compressed: "" (none), the name of
+	// a registered Compressor, "auto" (smallest encoded size), or
+	// "smallest-source" (smallest generated source).
+	Compress string
+	// Literal selects how embedded bytes are declared: "string" (a
+	// hex-escaped string literal, the default) or "bytes" (a []byte
+	// composite literal).
+	Literal string
+	// Dual embeds both the raw and compressed forms of the value, exposing
+	// "<name>Raw() io.ReadCloser" and "<name>Compressed() (io.ReadCloser,
+	// string)". It overrides Decl. Dual and Single are mutually exclusive.
+	Dual bool
+	// Single is like Dual, but embeds only the compressed form; the raw
+	// accessor decompresses lazily, trading CPU for a smaller output.
+	Single bool
+	// Export determines whether the declared name is exported.
+	Export bool
+	// Import is an optional extra package to import, usually combined with
+	// Type.
+	Import string
+	// Name is the name of the declared value. If empty, it defaults to
+	// "data", or, in fs mode, to the base name of Paths[0] or "assets".
+	Name string
+	// Package is the name of the generated file's package. Required.
+	Package string
+	// Type is the type of the declared value for "const" and "var" decls.
+	// It must be convertable to a string.
+	Type string
+	// Paths, if non-empty, switches to fs mode: one or more files, or a
+	// single directory to be walked recursively, are baked into a virtual
+	// file tree implementing fs.FS. Decl and Type are ignored, and data
+	// passed to Generate is ignored.
+	Paths []string
+	// Header, if set, is written verbatim as the leading comment, above the
+	// package clause. If empty, a generic "generated code" notice is used.
+	Header string
+}
+
+// Generate writes Go source declaring data (or, in fs mode, Options.Paths)
+// according to opts to w. The source is formatted with go/format.Source
+// before being written.
+func Generate(w io.Writer, data []byte, opts Options) error {
+	if opts.Dual && opts.Single {
+		return fmt.Errorf("bake: Dual and Single are mutually exclusive")
+	}
+	if (opts.Dual || opts.Single) && opts.Compress == "smallest-source" {
+		return fmt.Errorf("bake: Compress=\"smallest-source\" is not supported with Dual/Single")
+	}
+	if opts.Decl == "const" && opts.Literal == "bytes" && len(opts.Paths) == 0 && !opts.Dual && !opts.Single {
+		return fmt.Errorf(`bake: Decl="const" is incompatible with Literal="bytes" ([]byte composite literals are not constant expressions)`)
+	}
+	if opts.Package == "" {
+		return fmt.Errorf("bake: Package is required")
+	}
+
+	var o strings.Builder
+	writeComment(&o, opts.Header)
+	o.WriteString("package ")
+	o.WriteString(opts.Package)
+	o.WriteString("\n\n")
+
+	switch {
+	case len(opts.Paths) > 0 || opts.Decl == "fs" && !opts.Dual && !opts.Single:
+		if err := runFS(&o, opts.Name, opts.Export, opts.Compress, opts.Literal, opts.Paths); err != nil {
+			return err
+		}
+
+	case opts.Dual || opts.Single:
+		name := opts.Name
+		if name == "" {
+			name = "data"
+		}
+		name = getDeclName(name, opts.Export)
+		algo, compressor, _ := resolveCompressor(opts.Compress, data, nil)
+		reportDualMode(opts.Dual, algo, compressor, data)
+		runDual(&o, name, data, compressor, algo, opts.Single, opts.Literal)
+
+	default:
+		declaration := newDeclaration(opts.Decl)
+		typ := opts.Type
+		if _, ok := declaration.(funcDecl); ok {
+			typ = ""
+		}
+
+		name := opts.Name
+		if name == "" {
+			name = "data"
+		}
+		name = getDeclName(name, opts.Export)
+
+		_, compressor, declared := resolveCompressor(opts.Compress, data, func(c Compressor) string {
+			return declaration.FormatDeclare(data, name, typ, opts.Literal, c)
+		})
+
+		var imports []string
+		imports = append(imports, declaration.Imports()...)
+		if _, ok := declaration.(funcDecl); ok {
+			imports = append(imports, compressor.Imports()...)
+			imports = append(imports, readerImport(opts.Literal))
+		}
+		if opts.Import != "" {
+			imports = append(imports, opts.Import)
+		}
+		sort.Strings(imports)
+		if len(imports) > 0 {
+			writeImports(&o, imports)
+		}
+
+		if declared != "" {
+			o.WriteString(declared)
+		} else {
+			o.WriteString(declaration.FormatDeclare(data, name, typ, opts.Literal, compressor))
+		}
+	}
+
+	src, err := format.Source([]byte(o.String()))
+	if err != nil {
+		return fmt.Errorf("format source: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// writeComment writes the generated-file header comment. If header is
+// empty, a generic notice is written instead.
+func writeComment(o *strings.Builder, header string) {
+	if header == "" {
+		header = "// Code generated by gobake. DO NOT EDIT."
+	}
+	o.WriteString(header)
+	o.WriteString("\n\n")
+}
+
+// writeImports writes a sorted import block.
+func writeImports(o *strings.Builder, imports []string) {
+	o.WriteString("import (\n")
+	for _, imp := range imports {
+		fmt.Fprintf(o, "\t%#v\n", imp)
+	}
+	o.WriteString(")\n\n")
+}
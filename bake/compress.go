@@ -0,0 +1,275 @@
+package bake
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/anaminus/but"
+	"github.com/google/brotli/go/cbrotli"
+	"github.com/google/zopfli"
+)
+
+// Compressor encodes data for embedding, and describes how to decode it
+// again from generated Go source. Third parties may implement Compressor
+// and add it to the set selectable by name with RegisterCompressor.
+type Compressor interface {
+	// Encode actual data.
+	Encode([]byte) []byte
+	// Return list of packages required by function body.
+	Imports() []string
+	// Receive an expression that evaluates to an io.Reader over the
+	// compressed data, return a function body that decodes it.
+	FuncDecoder(string) string
+}
+
+type noCompressor struct{}
+
+func (noCompressor) Encode(b []byte) []byte {
+	return b
+}
+
+func (noCompressor) Imports() []string {
+	return []string{"io/ioutil"}
+}
+
+func (noCompressor) FuncDecoder(r string) string {
+	return "return ioutil.NopCloser(" + r + ")"
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Encode(b []byte) []byte {
+	var buf strings.Builder
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(b)
+	but.IfFatal(err, "write gzip")
+	but.IfFatal(w.Close(), "close gzip")
+	return []byte(buf.String())
+}
+
+func (gzipCompressor) Imports() []string {
+	return []string{"compress/gzip"}
+}
+
+func (gzipCompressor) FuncDecoder(r string) string {
+	return "gr, _ := gzip.NewReader(" + r + ")\n\treturn gr"
+}
+
+type flateCompressor struct{}
+
+func (flateCompressor) Encode(b []byte) []byte {
+	var buf strings.Builder
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	but.IfFatal(err, "open flate")
+	_, err = w.Write(b)
+	but.IfFatal(err, "write flate")
+	but.IfFatal(w.Close(), "close flate")
+	return []byte(buf.String())
+}
+
+func (flateCompressor) Imports() []string {
+	return []string{"compress/flate"}
+}
+
+func (flateCompressor) FuncDecoder(r string) string {
+	return "return flate.NewReader(" + r + ")"
+}
+
+// brotliCompressor encodes with the reference cbrotli bindings, which
+// typically beat gzip and flate by 15-25% on static web assets at the cost
+// of slower encoding.
+type brotliCompressor struct{}
+
+func (brotliCompressor) Encode(b []byte) []byte {
+	out, err := cbrotli.Encode(b, cbrotli.WriterOptions{Quality: 11})
+	but.IfFatal(err, "encode brotli")
+	return out
+}
+
+func (brotliCompressor) Imports() []string {
+	return []string{"github.com/google/brotli/go/cbrotli"}
+}
+
+func (brotliCompressor) FuncDecoder(r string) string {
+	return "return cbrotli.NewReader(" + r + ")"
+}
+
+// zopfliCompressor encodes with zopfli's exhaustive DEFLATE search, which
+// produces smaller output than flate.BestCompression at the cost of much
+// slower encoding. Zopfli output is plain DEFLATE, so it decodes with the
+// standard compress/flate reader.
+type zopfliCompressor struct{}
+
+func (zopfliCompressor) Encode(b []byte) []byte {
+	var buf strings.Builder
+	but.IfFatal(zopfli.Deflate(&buf, b, zopfli.DefaultOptions()), "encode zopfli")
+	return []byte(buf.String())
+}
+
+func (zopfliCompressor) Imports() []string {
+	return []string{"compress/flate"}
+}
+
+func (zopfliCompressor) FuncDecoder(r string) string {
+	return "return flate.NewReader(" + r + ")"
+}
+
+// namedCompressor pairs a Compressor with the -compress flag value that
+// selects it.
+type namedCompressor struct {
+	name string
+	c    Compressor
+}
+
+// compressors lists every selectable compressor, in the order "auto" and
+// "smallest-source" try them. "" (none) always comes first, so it wins
+// ties against compressed output that doesn't actually save space.
+var compressors = []namedCompressor{
+	{"", noCompressor{}},
+	{"gzip", gzipCompressor{}},
+	{"flate", flateCompressor{}},
+	{"brotli", brotliCompressor{}},
+	{"zopfli", zopfliCompressor{}},
+}
+
+// RegisterCompressor adds c to the set of compressors selectable by name via
+// Options.Compress. Registering a name that already exists replaces it.
+// RegisterCompressor is not safe to call concurrently with Generate.
+func RegisterCompressor(name string, c Compressor) {
+	for i, nc := range compressors {
+		if nc.name == name {
+			compressors[i].c = c
+			return
+		}
+	}
+	compressors = append(compressors, namedCompressor{name, c})
+}
+
+func newCompressor(name string) Compressor {
+	for _, nc := range compressors {
+		if nc.name == name {
+			return nc.c
+		}
+	}
+	return noCompressor{}
+}
+
+// pickSmallestEncoded runs data through every compressor and returns the one
+// producing the smallest encoded byte slice.
+func pickSmallestEncoded(data []byte) (name string, c Compressor, encoded []byte) {
+	name, c, encoded = compressors[0].name, compressors[0].c, compressors[0].c.Encode(data)
+	for _, nc := range compressors[1:] {
+		enc := nc.c.Encode(data)
+		if len(enc) < len(encoded) {
+			name, c, encoded = nc.name, nc.c, enc
+		}
+	}
+	return name, c, encoded
+}
+
+// pickSmallestSource formats data with every compressor using format, and
+// returns the one producing the smallest generated source text. Unlike
+// pickSmallestEncoded, this compares the formatted Go source rather than
+// the raw encoded bytes, since the two don't necessarily correlate once the
+// declaration is rendered.
+func pickSmallestSource(data []byte, format func(Compressor) string) (name string, c Compressor, source string) {
+	name, c = compressors[0].name, compressors[0].c
+	source = format(c)
+	for _, nc := range compressors[1:] {
+		s := format(nc.c)
+		if len(s) < len(source) {
+			name, c, source = nc.name, nc.c, s
+		}
+	}
+	return name, c, source
+}
+
+// normalizeCompressorName returns name if it names a known compressor, or
+// "" (none) otherwise.
+func normalizeCompressorName(name string) string {
+	for _, nc := range compressors {
+		if nc.name == name {
+			return name
+		}
+	}
+	return ""
+}
+
+// contentEncoding returns the HTTP Content-Encoding token produced by the
+// named compressor. Zopfli produces a plain DEFLATE stream, so it shares
+// flate's token. Any other name, including one added with
+// RegisterCompressor, reports "identity".
+//
+// Note that flate and zopfli both emit a raw DEFLATE stream (RFC 1951), but
+// "deflate" is historically ambiguous over HTTP: many clients, including
+// most browsers, expect the zlib-wrapped variant (RFC 1950) instead. Passing
+// either compressor's output straight through as Content-Encoding: deflate
+// is therefore not reliably portable; gzip or brotli should be preferred
+// wherever the compressed bytes may reach an HTTP client directly.
+func contentEncoding(algo string) string {
+	switch algo {
+	case "gzip":
+		return "gzip"
+	case "flate", "zopfli":
+		return "deflate"
+	case "brotli":
+		return "br"
+	default:
+		return "identity"
+	}
+}
+
+// resolveCompressor picks a Compressor for data according to mode, one of
+// "" (none), a specific compressor name, "auto", or "smallest-source".
+// format renders the final declaration for a candidate Compressor; it is
+// only required for "smallest-source", and may be nil otherwise. The
+// returned source is non-empty only for "smallest-source", in which case it
+// is the already-rendered declaration and need not be formatted again.
+func resolveCompressor(mode string, data []byte, format func(Compressor) string) (name string, c Compressor, source string) {
+	switch mode {
+	case "auto":
+		var encoded []byte
+		name, c, encoded = pickSmallestEncoded(data)
+		reportChosenCompressor(mode, name, len(encoded), len(data))
+	case "smallest-source":
+		name, c, source = pickSmallestSource(data, format)
+		reportChosenCompressor(mode, name, len(c.Encode(data)), len(data))
+	default:
+		name = normalizeCompressorName(mode)
+		c = newCompressor(name)
+	}
+	return name, c, source
+}
+
+// reportDualMode prints a summary of what -dual or -single embedded.
+func reportDualMode(dual bool, algo string, c Compressor, data []byte) {
+	label := algo
+	if label == "" {
+		label = "none"
+	}
+	encoded := len(c.Encode(data))
+	if dual {
+		fmt.Fprintf(os.Stderr, "gobake: -dual embedding %q raw (%d bytes) and compressed (%d bytes)\n",
+			label, len(data), encoded)
+	} else {
+		fmt.Fprintf(os.Stderr, "gobake: -single embedding %q compressed only (%d bytes, decompressed on Raw access)\n",
+			label, encoded)
+	}
+}
+
+// reportChosenCompressor prints the compressor that auto-selection chose,
+// and how much smaller it is than storing the data uncompressed.
+func reportChosenCompressor(mode, name string, chosenSize, uncompressedSize int) {
+	if name == "" {
+		name = "none"
+	}
+	var savings float64
+	if uncompressedSize > 0 {
+		savings = 100 * (1 - float64(chosenSize)/float64(uncompressedSize))
+	}
+	fmt.Fprintf(os.Stderr, "gobake: -compress=%s selected %q (%d bytes, %.1f%% smaller than uncompressed)\n",
+		mode, name, chosenSize, savings)
+}
@@ -0,0 +1,97 @@
+package bake
+
+import "unicode"
+
+// Declaration renders a baked value as a Go declaration. Third parties may
+// implement Declaration and add it to the set selectable by name with
+// RegisterDeclaration.
+type Declaration interface {
+	// Return list of package required by declaration.
+	Imports() []string
+	// Return declaration. Literal is "string" or "bytes".
+	FormatDeclare(value []byte, name, typ, literal string, compress Compressor) string
+}
+
+type constDecl struct{}
+
+func (constDecl) Imports() []string { return nil }
+func (constDecl) FormatDeclare(value []byte, name, typ, literal string, compress Compressor) string {
+	return "const " + name + " = " + formatLiteral(16, 1, typ, literal, compress.Encode(value))
+}
+
+type varDecl struct{}
+
+func (varDecl) Imports() []string { return nil }
+func (varDecl) FormatDeclare(value []byte, name, typ, literal string, compress Compressor) string {
+	return "var " + name + " = " + formatLiteral(16, 1, typ, literal, compress.Encode(value))
+}
+
+type funcDecl struct{}
+
+func (funcDecl) Imports() []string { return []string{"io"} }
+func (funcDecl) FormatDeclare(value []byte, name, typ, literal string, compress Compressor) string {
+	return `func ` + name + "() io.ReadCloser {\n\t" + declKeyword(literal) + " a = " +
+		formatLiteral(16, 2, typ, literal, compress.Encode(value)) +
+		"\t" + compress.FuncDecoder(readerExpr(literal, "a")) + "\n}\n"
+}
+
+// namedDeclaration pairs a Declaration with the -decl flag value that
+// selects it.
+type namedDeclaration struct {
+	name string
+	d    Declaration
+}
+
+// declarations lists every selectable declaration, excluding "fs", which is
+// generated by its own code path rather than through Declaration.
+var declarations = []namedDeclaration{
+	{"const", constDecl{}},
+	{"var", varDecl{}},
+	{"func", funcDecl{}},
+}
+
+// RegisterDeclaration adds d to the set of declarations selectable by name
+// via Options.Decl. Registering a name that already exists replaces it.
+// RegisterDeclaration is not safe to call concurrently with Generate.
+func RegisterDeclaration(name string, d Declaration) {
+	for i, nd := range declarations {
+		if nd.name == name {
+			declarations[i].d = d
+			return
+		}
+	}
+	declarations = append(declarations, namedDeclaration{name, d})
+}
+
+func newDeclaration(name string) Declaration {
+	for _, nd := range declarations {
+		if nd.name == name {
+			return nd.d
+		}
+	}
+	return funcDecl{}
+}
+
+// Sanitize a string so that it's suitable as a variable name.
+func getDeclName(name string, export bool) string {
+	s := []rune{}
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r):
+			if len(s) == 0 {
+				if export {
+					s = append(s, unicode.ToUpper(r))
+				} else {
+					s = append(s, unicode.ToLower(r))
+				}
+			} else {
+				s = append(s, r)
+			}
+		default:
+			if len(s) > 0 {
+				s = append(s, '_')
+			}
+		}
+	}
+	return string(s)
+}
@@ -0,0 +1,51 @@
+package bake
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// runDual writes a pair of accessor functions for name, exposing both the
+// raw and compressed forms of b:
+//
+//	func <name>Raw() io.ReadCloser
+//	func <name>Compressed() (io.ReadCloser, string)
+//
+// The returned encoding is an HTTP Content-Encoding token, letting a caller
+// pass the compressed form straight through to a client that accepts it
+// instead of re-compressing on every request. See contentEncoding for a
+// caveat about "deflate" (flate/zopfli) pass-through portability.
+//
+// If single is false ("-dual"), both the raw and compressed bytes are
+// embedded, so Raw and Compressed both read embedded bytes directly, with no
+// decompression.
+// If single is true ("-single"), only the compressed bytes are embedded,
+// trading a decompression on every Raw call for a smaller binary.
+//
+// Literal selects how the embedded bytes are declared ("string" or "bytes").
+func runDual(o *strings.Builder, name string, b []byte, compressor Compressor, algo string, single bool, literal string) {
+	encoding := contentEncoding(algo)
+
+	var imports []string
+	imports = append(imports, "io", "io/ioutil", readerImport(literal))
+	if single {
+		imports = append(imports, compressor.Imports()...)
+	}
+	sort.Strings(imports)
+	writeImports(o, dedup(imports))
+
+	keyword := declKeyword(literal)
+
+	if single {
+		fmt.Fprintf(o, "%s %sData = %s\n\n", keyword, name, strings.TrimSuffix(formatLiteral(16, 1, "", literal, compressor.Encode(b)), "\n"))
+		fmt.Fprintf(o, "func %sRaw() io.ReadCloser {\n\t%s\n}\n\n", name, compressor.FuncDecoder(readerExpr(literal, name+"Data")))
+		fmt.Fprintf(o, "func %sCompressed() (io.ReadCloser, string) {\n\treturn ioutil.NopCloser(%s), %#v\n}\n", name, readerExpr(literal, name+"Data"), encoding)
+		return
+	}
+
+	fmt.Fprintf(o, "%s %sRawData = %s\n\n", keyword, name, strings.TrimSuffix(formatLiteral(16, 1, "", literal, b), "\n"))
+	fmt.Fprintf(o, "%s %sCompressedData = %s\n\n", keyword, name, strings.TrimSuffix(formatLiteral(16, 1, "", literal, compressor.Encode(b)), "\n"))
+	fmt.Fprintf(o, "func %sRaw() io.ReadCloser {\n\treturn ioutil.NopCloser(%s)\n}\n\n", name, readerExpr(literal, name+"RawData"))
+	fmt.Fprintf(o, "func %sCompressed() (io.ReadCloser, string) {\n\treturn ioutil.NopCloser(%s), %#v\n}\n", name, readerExpr(literal, name+"CompressedData"), encoding)
+}
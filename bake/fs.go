@@ -0,0 +1,334 @@
+package bake
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// fsEntry describes a single file or directory collected for "-decl=fs"
+// mode. Entries are keyed by slash-separated path relative to the root,
+// with the root itself keyed as ".". algo and encoded are filled in by
+// runFS once a compressor has been chosen for the entry.
+type fsEntry struct {
+	isDir    bool
+	size     int64
+	modTime  time.Time
+	data     []byte
+	children []string // sorted base names of direct children; dirs only
+	algo     string   // name of the compressor chosen for this file
+	encoded  []byte   // data, as encoded by the chosen compressor
+}
+
+// collectFS walks paths and returns a tree of fsEntry values rooted at ".".
+// A single directory argument is walked recursively, preserving its
+// structure. Multiple arguments are each added as a top-level entry named
+// by their base name.
+func collectFS(paths []string) (map[string]*fsEntry, error) {
+	entries := map[string]*fsEntry{
+		".": {isDir: true},
+	}
+
+	addChild := func(parent, name string) {
+		e := entries[parent]
+		for _, c := range e.children {
+			if c == name {
+				return
+			}
+		}
+		e.children = append(e.children, name)
+	}
+	linkParent := func(p string) {
+		parent := path.Dir(p)
+		if parent == "." || parent == "/" {
+			parent = "."
+		}
+		addChild(parent, path.Base(p))
+	}
+
+	if len(paths) == 1 {
+		if info, err := os.Stat(paths[0]); err == nil && info.IsDir() {
+			root := paths[0]
+			err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				rel, err := filepath.Rel(root, p)
+				if err != nil {
+					return err
+				}
+				rel = filepath.ToSlash(rel)
+				if rel == "." {
+					entries["."].modTime = info.ModTime()
+					return nil
+				}
+				if info.IsDir() {
+					entries[rel] = &fsEntry{isDir: true, modTime: info.ModTime()}
+					linkParent(rel)
+					return nil
+				}
+				b, err := ioutil.ReadFile(p)
+				if err != nil {
+					return err
+				}
+				entries[rel] = &fsEntry{size: info.Size(), modTime: info.ModTime(), data: b}
+				linkParent(rel)
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range entries {
+				sort.Strings(e.children)
+			}
+			return entries, nil
+		}
+	}
+
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("%s: directories may only be baked alone", p)
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		name := filepath.Base(p)
+		entries[name] = &fsEntry{size: info.Size(), modTime: info.ModTime(), data: b}
+		linkParent(name)
+	}
+	// Derive the synthetic root's modTime from its children instead of
+	// wall-clock time, so the generated source is reproducible.
+	for _, name := range entries["."].children {
+		if t := entries[name].modTime; t.After(entries["."].modTime) {
+			entries["."].modTime = t
+		}
+	}
+	for _, e := range entries {
+		sort.Strings(e.children)
+	}
+	return entries, nil
+}
+
+// runFS collects paths into a virtual file tree and writes a Go source
+// fragment declaring a fs.FS implementation over it. compressMode is the
+// raw "-compress" flag value; "auto" and "smallest-source" pick a
+// compressor independently for each file.
+func runFS(o *strings.Builder, name string, export bool, compressMode, literal string, paths []string) error {
+	if len(paths) == 0 {
+		return fmt.Errorf("decl=fs requires at least one file or directory argument")
+	}
+	entries, err := collectFS(paths)
+	if err != nil {
+		return fmt.Errorf("collect files: %w", err)
+	}
+
+	auto := compressMode == "auto" || compressMode == "smallest-source"
+	usage := map[string]int{}
+	var totalRaw, totalEncoded int
+	for _, e := range entries {
+		if e.isDir {
+			continue
+		}
+		var c Compressor
+		if compressMode == "smallest-source" {
+			e.algo, c, _ = pickSmallestSource(e.data, func(c Compressor) string {
+				return formatLiteral(0, 0, "", literal, c.Encode(e.data))
+			})
+			e.encoded = c.Encode(e.data)
+		} else if auto {
+			e.algo, c, e.encoded = pickSmallestEncoded(e.data)
+		} else {
+			e.algo = normalizeCompressorName(compressMode)
+			c = newCompressor(e.algo)
+			e.encoded = c.Encode(e.data)
+		}
+		usage[e.algo]++
+		totalRaw += len(e.data)
+		totalEncoded += len(e.encoded)
+	}
+	if auto {
+		reportChosenCompressor(compressMode, summarizeUsage(usage), totalEncoded, totalRaw)
+	}
+
+	if name == "" {
+		if len(paths) == 1 {
+			name = filepath.Base(paths[0])
+			name = name[:len(name)-len(filepath.Ext(name))]
+		} else {
+			name = "assets"
+		}
+	}
+	prefix := getDeclName(name, export)
+
+	imports := []string{"io", "io/fs", "path", "time"}
+	if len(usage) > 0 {
+		// The reader-over-data expression is only referenced by the
+		// per-file decode functions, which exist only if there's at least
+		// one regular file to decode.
+		imports = append(imports, readerImport(literal))
+	}
+	for _, nc := range compressors {
+		if usage[nc.name] > 0 {
+			imports = append(imports, nc.c.Imports()...)
+		}
+	}
+	sort.Strings(imports)
+	writeImports(o, dedup(imports))
+
+	o.WriteString(formatFS(entries, prefix, literal))
+	return nil
+}
+
+// summarizeUsage renders the compressors chosen for a "-compress=auto" or
+// "-compress=smallest-source" fs bake, e.g. "none: 1, gzip: 3".
+func summarizeUsage(usage map[string]int) string {
+	var names []string
+	for _, nc := range compressors {
+		if usage[nc.name] > 0 {
+			names = append(names, nc.name)
+		}
+	}
+	var parts []string
+	for _, name := range names {
+		label := name
+		if label == "" {
+			label = "none"
+		}
+		parts = append(parts, fmt.Sprintf("%s: %d", label, usage[name]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// dedup removes consecutive duplicate strings from a sorted slice.
+func dedup(s []string) []string {
+	out := s[:0]
+	for i, v := range s {
+		if i == 0 || v != out[len(out)-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// decodeFuncName derives a Go identifier for the decode function of a named
+// compressor, e.g. "gzip" -> "Gzip", "" -> "None".
+func decodeFuncName(algo string) string {
+	if algo == "" {
+		return "None"
+	}
+	r := []rune(algo)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// formatFS renders entries as a self-contained fs.FS implementation named
+// after prefix. The result implements fs.FS directly; wrap a value of the
+// generated FS type with http.FS to obtain an http.FileSystem.
+func formatFS(entries map[string]*fsEntry, prefix, literal string) string {
+	dataType := "string"
+	if literal == "bytes" {
+		dataType = "[]byte"
+	}
+
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	algosUsed := map[string]Compressor{}
+	for _, k := range keys {
+		if e := entries[k]; !e.isDir {
+			algosUsed[e.algo] = newCompressor(e.algo)
+		}
+	}
+	var algoNames []string
+	for _, nc := range compressors {
+		if _, ok := algosUsed[nc.name]; ok {
+			algoNames = append(algoNames, nc.name)
+		}
+	}
+
+	var s strings.Builder
+
+	fmt.Fprintf(&s, "type %sFileInfo struct {\n\tname    string\n\tsize    int64\n\tmodTime time.Time\n\tisDir   bool\n}\n\n", prefix)
+	fmt.Fprintf(&s, "func (i *%sFileInfo) Name() string       { return i.name }\n", prefix)
+	fmt.Fprintf(&s, "func (i *%sFileInfo) Size() int64        { return i.size }\n", prefix)
+	fmt.Fprintf(&s, "func (i *%sFileInfo) ModTime() time.Time { return i.modTime }\n", prefix)
+	fmt.Fprintf(&s, "func (i *%sFileInfo) IsDir() bool        { return i.isDir }\n", prefix)
+	fmt.Fprintf(&s, "func (i *%sFileInfo) Sys() interface{}   { return nil }\n", prefix)
+	fmt.Fprintf(&s, "func (i *%sFileInfo) Mode() fs.FileMode {\n\tif i.isDir {\n\t\treturn fs.ModeDir | 0555\n\t}\n\treturn 0444\n}\n\n", prefix)
+
+	fmt.Fprintf(&s, "type %sDirEntry struct{ info *%sFileInfo }\n\n", prefix, prefix)
+	fmt.Fprintf(&s, "func (d %sDirEntry) Name() string               { return d.info.name }\n", prefix)
+	fmt.Fprintf(&s, "func (d %sDirEntry) IsDir() bool                { return d.info.isDir }\n", prefix)
+	fmt.Fprintf(&s, "func (d %sDirEntry) Type() fs.FileMode          { return d.info.Mode().Type() }\n", prefix)
+	fmt.Fprintf(&s, "func (d %sDirEntry) Info() (fs.FileInfo, error) { return d.info, nil }\n\n", prefix)
+
+	for _, algo := range algoNames {
+		fmt.Fprintf(&s, "func %sDecode%s(a %s) io.ReadCloser {\n\t%s\n}\n\n", prefix, decodeFuncName(algo), dataType, algosUsed[algo].FuncDecoder(readerExpr(literal, "a")))
+	}
+
+	fmt.Fprintf(&s, "type %sEntry struct {\n\tinfo     %sFileInfo\n\tdata     %s\n\tdecode   func(%s) io.ReadCloser\n\tchildren []string\n}\n\n", prefix, prefix, dataType, dataType)
+
+	fmt.Fprintf(&s, "var %sEntries = map[string]*%sEntry{\n", prefix, prefix)
+	for _, k := range keys {
+		e := entries[k]
+		fmt.Fprintf(&s, "\t%#v: {\n", k)
+		fmt.Fprintf(&s, "\t\tinfo: %sFileInfo{name: %#v, size: %d, modTime: time.Unix(%d, 0), isDir: %v},\n",
+			prefix, path.Base(k), len(e.data), e.modTime.Unix(), e.isDir)
+		if e.isDir {
+			fmt.Fprintf(&s, "\t\tchildren: %#v,\n", e.children)
+		} else {
+			data := strings.TrimSuffix(formatLiteral(0, 0, "", literal, e.encoded), "\n")
+			fmt.Fprintf(&s, "\t\tdata: %s,\n", data)
+			fmt.Fprintf(&s, "\t\tdecode: %sDecode%s,\n", prefix, decodeFuncName(e.algo))
+		}
+		s.WriteString("\t},\n")
+	}
+	s.WriteString("}\n\n")
+
+	fmt.Fprintf(&s, "// %sFS implements fs.FS over the files baked into %sEntries. Wrap a value\n", prefix, prefix)
+	fmt.Fprintf(&s, "// of this type with http.FS to obtain an http.FileSystem.\n")
+	fmt.Fprintf(&s, "type %sFS struct{}\n\n", prefix)
+
+	fmt.Fprintf(&s, "func (%sFS) Open(name string) (fs.File, error) {\n", prefix)
+	s.WriteString("\tif !fs.ValidPath(name) {\n\t\treturn nil, &fs.PathError{Op: \"open\", Path: name, Err: fs.ErrInvalid}\n\t}\n")
+	fmt.Fprintf(&s, "\te, ok := %sEntries[name]\n", prefix)
+	s.WriteString("\tif !ok {\n\t\treturn nil, &fs.PathError{Op: \"open\", Path: name, Err: fs.ErrNotExist}\n\t}\n")
+	fmt.Fprintf(&s, "\tif e.info.isDir {\n\t\treturn &%sDir{entry: e, name: name}, nil\n\t}\n", prefix)
+	fmt.Fprintf(&s, "\treturn &%sFile{entry: e, r: e.decode(e.data)}, nil\n}\n\n", prefix)
+
+	fmt.Fprintf(&s, "type %sFile struct {\n\tentry *%sEntry\n\tr     io.ReadCloser\n}\n\n", prefix, prefix)
+	fmt.Fprintf(&s, "func (f *%sFile) Stat() (fs.FileInfo, error) { return &f.entry.info, nil }\n", prefix)
+	fmt.Fprintf(&s, "func (f *%sFile) Read(b []byte) (int, error) { return f.r.Read(b) }\n", prefix)
+	fmt.Fprintf(&s, "func (f *%sFile) Close() error               { return f.r.Close() }\n\n", prefix)
+
+	fmt.Fprintf(&s, "type %sDir struct {\n\tentry *%sEntry\n\tname  string\n\tpos   int\n}\n\n", prefix, prefix)
+	fmt.Fprintf(&s, "func (d *%sDir) Stat() (fs.FileInfo, error) { return &d.entry.info, nil }\n", prefix)
+	fmt.Fprintf(&s, "func (d *%sDir) Read([]byte) (int, error) {\n\treturn 0, &fs.PathError{Op: \"read\", Path: d.name, Err: fs.ErrInvalid}\n}\n", prefix)
+	fmt.Fprintf(&s, "func (d *%sDir) Close() error { return nil }\n\n", prefix)
+
+	fmt.Fprintf(&s, "func (d *%sDir) ReadDir(n int) ([]fs.DirEntry, error) {\n", prefix)
+	s.WriteString("\tchildren := d.entry.children\n")
+	s.WriteString("\tif n <= 0 {\n\t\tn = len(children) - d.pos\n\t}\n")
+	s.WriteString("\tif d.pos >= len(children) {\n\t\tif n > 0 {\n\t\t\treturn nil, io.EOF\n\t\t}\n\t\treturn nil, nil\n\t}\n")
+	fmt.Fprintf(&s, "\tlist := make([]fs.DirEntry, 0, n)\n")
+	s.WriteString("\tfor ; d.pos < len(children) && len(list) < n; d.pos++ {\n")
+	s.WriteString("\t\tfull := path.Join(d.name, children[d.pos])\n")
+	fmt.Fprintf(&s, "\t\tlist = append(list, %sDirEntry{info: &%sEntries[full].info})\n", prefix, prefix)
+	s.WriteString("\t}\n\treturn list, nil\n}\n")
+
+	return s.String()
+}
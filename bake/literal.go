@@ -0,0 +1,130 @@
+package bake
+
+import (
+	"fmt"
+	"strings"
+)
+
+// formatValue formats b as a hex-escaped string literal, wrapping at "wrap"
+// bytes per line (0 disables wrapping). Typ specifies an optional type that
+// encloses the generated string.
+func formatValue(wrap, indent int, typ string, b []byte) string {
+	const hextable = "0123456789abcdef"
+	if typ == "string" {
+		typ = ""
+	}
+	if len(b) == 0 {
+		if typ == "" {
+			return "\"\"\n"
+		}
+		return typ + "(\"\")\n"
+	}
+	var s strings.Builder
+	if typ != "" {
+		s.WriteString(typ)
+		s.WriteByte('(')
+	}
+	if wrap > 0 && len(b) > wrap {
+		s.WriteString("\"\" +\n")
+	} else {
+		s.WriteByte('"')
+	}
+	for i := 0; i < len(b); i++ {
+		if len(b) > wrap && wrap > 0 && i%wrap == 0 {
+			for i := 0; i < indent; i++ {
+				s.WriteString("\t")
+			}
+			s.WriteString("\"")
+		}
+		s.WriteString("\\x")
+		s.WriteByte(hextable[b[i]>>4])
+		s.WriteByte(hextable[b[i]&0x0f])
+		if i == len(b)-1 {
+			s.WriteByte('"')
+			if typ != "" {
+				s.WriteByte(')')
+			}
+			s.WriteByte('\n')
+		} else if wrap > 0 && i%wrap == wrap-1 {
+			s.WriteString("\" +\n")
+		}
+	}
+	return s.String()
+}
+
+// formatBytesValue formats b as a []byte composite literal, wrapping at
+// "wrap" bytes per line (0 disables wrapping). Typ specifies an optional
+// type that the result is converted to.
+func formatBytesValue(wrap, indent int, typ string, b []byte) string {
+	var s strings.Builder
+	if typ != "" {
+		s.WriteString(typ)
+		s.WriteByte('(')
+	}
+	if len(b) == 0 {
+		s.WriteString("[]byte{}")
+	} else {
+		s.WriteString("[]byte{\n")
+		for i, c := range b {
+			if wrap <= 0 || i%wrap == 0 {
+				for j := 0; j < indent; j++ {
+					s.WriteString("\t")
+				}
+			}
+			fmt.Fprintf(&s, "0x%02x,", c)
+			if wrap > 0 && i%wrap == wrap-1 {
+				s.WriteByte('\n')
+			} else {
+				s.WriteByte(' ')
+			}
+		}
+		if wrap <= 0 || len(b)%wrap != 0 {
+			s.WriteByte('\n')
+		}
+		for j := 0; j < indent-1; j++ {
+			s.WriteString("\t")
+		}
+		s.WriteString("}")
+	}
+	if typ != "" {
+		s.WriteByte(')')
+	}
+	s.WriteByte('\n')
+	return s.String()
+}
+
+// formatLiteral formats b as either a hex-escaped string literal or a
+// []byte composite literal, according to literal ("string" or "bytes").
+func formatLiteral(wrap, indent int, typ, literal string, b []byte) string {
+	if literal == "bytes" {
+		return formatBytesValue(wrap, indent, typ, b)
+	}
+	return formatValue(wrap, indent, typ, b)
+}
+
+// readerExpr returns a Go expression evaluating to an io.Reader over the
+// value named v, matching how v was declared for the given literal kind.
+func readerExpr(literal, v string) string {
+	if literal == "bytes" {
+		return "bytes.NewReader(" + v + ")"
+	}
+	return "strings.NewReader(" + v + ")"
+}
+
+// readerImport returns the package that readerExpr's result depends on.
+func readerImport(literal string) string {
+	if literal == "bytes" {
+		return "bytes"
+	}
+	return "strings"
+}
+
+// declKeyword returns the declaration keyword to use for a value formatted
+// with the given literal kind. A []byte composite literal is not a constant
+// expression, so "bytes" must be declared with "var" rather than "const".
+func declKeyword(literal string) string {
+	if literal == "bytes" {
+		return "var"
+	}
+	return "const"
+}
@@ -0,0 +1,135 @@
+// Command gobake embeds the contents of a file, or a directory tree, as Go
+// source. See bake.Options for what each flag controls.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anaminus/but"
+	"github.com/anaminus/gobake/bake"
+)
+
+func main() {
+	var flags struct {
+		Decl     string
+		Compress string
+		Literal  string
+		Dual     bool
+		Single   bool
+		Export   bool
+		Import   string
+		Name     string
+		Output   string
+		Package  string
+		Type     string
+	}
+
+	flag.StringVar(&flags.Decl, "decl", "func", `How to declare the value. Can be "func", "const", "var", or "fs".`)
+	flag.StringVar(&flags.Compress, "compress", "", `How to compress the value. Can be "" (none), "gzip", "flate", "brotli", "zopfli", `+
+		`"auto" (smallest encoded size), or "smallest-source" (smallest generated source).`)
+	flag.StringVar(&flags.Literal, "literal", "string", `How to declare embedded bytes. Can be "string" (a hex-escaped string `+
+		`literal) or "bytes" (a []byte composite literal).`)
+	flag.BoolVar(&flags.Dual, "dual", false, `Embed both the raw and compressed forms, exposing "<name>Raw() io.ReadCloser" and `+
+		`"<name>Compressed() (io.ReadCloser, string)". Overrides -decl.`)
+	flag.BoolVar(&flags.Single, "single", false, `Like -dual, but embed only the compressed form; the raw accessor decompresses `+
+		`lazily, trading CPU for a smaller binary. Overrides -decl.`)
+	flag.BoolVar(&flags.Export, "export", false, `Whether the declaration should be exported.`)
+	flag.StringVar(&flags.Import, "import", "", `An optional package to import. Usually combined with -type.`)
+	flag.StringVar(&flags.Name, "name", "", `The name of the declared value. Defaults to the name of the input file.`)
+	flag.StringVar(&flags.Output, "output", "", `The name of the generated file. Writes to stdout if empty.`)
+	flag.StringVar(&flags.Package, "package", "", `The name of the package. Determined by output location if empty, or "main" if all else fails.`)
+	flag.StringVar(&flags.Type, "type", "string", `The type of the declared value. Must be convertable to a string.`)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: gobake [options] [file...]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Reads from stdin if file is omitted.")
+		fmt.Fprintln(os.Stderr, `With "-decl=fs", one or more files or a single directory may be given;`)
+		fmt.Fprintln(os.Stderr, "a directory is walked recursively to produce a virtual file tree.")
+		fmt.Fprintln(os.Stderr)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flags.Package == "" {
+		if flags.Output == "" {
+			flags.Package = "main"
+		} else {
+			pkg, err := build.ImportDir(filepath.Dir(flags.Output), 0)
+			if err != nil || pkg.Name == "" {
+				flags.Package = "main"
+			} else {
+				flags.Package = pkg.Name
+			}
+		}
+	}
+
+	opts := bake.Options{
+		Decl:     flags.Decl,
+		Compress: flags.Compress,
+		Literal:  flags.Literal,
+		Dual:     flags.Dual,
+		Single:   flags.Single,
+		Export:   flags.Export,
+		Import:   flags.Import,
+		Name:     flags.Name,
+		Package:  flags.Package,
+		Type:     flags.Type,
+		Header:   commandLineComment(),
+	}
+
+	var data []byte
+	if flags.Decl == "fs" && !flags.Dual && !flags.Single {
+		opts.Paths = flag.Args()
+	} else {
+		data = readInput(&opts, flags.Export)
+	}
+
+	var o strings.Builder
+	but.IfFatal(bake.Generate(&o, data, opts), "generate")
+
+	if flags.Output == "" {
+		_, err := os.Stdout.Write([]byte(o.String()))
+		but.IfFatal(err, "write stdout")
+	} else {
+		but.IfFatal(ioutil.WriteFile(flags.Output, []byte(o.String()), 0666), "write file")
+	}
+}
+
+// readInput reads the bytes to bake from flag.Arg(0), or from stdin if no
+// file argument was given, deriving opts.Name from it if unset.
+func readInput(opts *bake.Options, export bool) []byte {
+	if flag.NArg() == 0 {
+		b, err := ioutil.ReadAll(os.Stdin)
+		but.IfFatal(err, "read stdin")
+		if opts.Name == "" {
+			opts.Name = "stdin"
+		}
+		return b
+	}
+	b, err := ioutil.ReadFile(flag.Arg(0))
+	but.IfFatal(err, "read file")
+	if opts.Name == "" {
+		name := filepath.Base(flag.Arg(0))
+		opts.Name = name[:len(name)-len(filepath.Ext(name))]
+	}
+	return b
+}
+
+// commandLineComment reproduces the command line that invoked gobake, for
+// use as the generated file's header comment.
+func commandLineComment() string {
+	var s strings.Builder
+	s.WriteString("// File generated by \"gobake")
+	for i := 1; i < len(os.Args); i++ {
+		s.WriteByte(' ')
+		s.WriteString(os.Args[i])
+	}
+	s.WriteString("\"\n// DO NOT EDIT!")
+	return s.String()
+}